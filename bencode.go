@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// torrentInfoSpan finds the byte range of the top-level "info" value in a
+// bencoded .torrent file. Bencode's string/int/list/dict encodings are
+// canonical, so hashing that exact byte range (rather than decoding and
+// re-encoding it) reproduces the info-hash a tracker or client would
+// compute for the torrent.
+func torrentInfoSpan(data []byte) (start, end int, err error) {
+	if len(data) == 0 || data[0] != 'd' {
+		return 0, 0, fmt.Errorf("bencode: not a dictionary")
+	}
+
+	i := 1
+	for i < len(data) && data[i] != 'e' {
+		key, next, err := bencodeString(data, i)
+		if err != nil {
+			return 0, 0, err
+		}
+		i = next
+
+		valStart := i
+		valEnd, err := bencodeSkip(data, i)
+		if err != nil {
+			return 0, 0, err
+		}
+		if key == "info" {
+			return valStart, valEnd, nil
+		}
+		i = valEnd
+	}
+	return 0, 0, fmt.Errorf("bencode: no info dictionary")
+}
+
+// bencodeSkip returns the offset just past the bencode value starting at i.
+func bencodeSkip(data []byte, i int) (int, error) {
+	if i >= len(data) {
+		return 0, fmt.Errorf("bencode: unexpected end of input")
+	}
+
+	switch {
+	case data[i] == 'i':
+		j := i + 1
+		for j < len(data) && data[j] != 'e' {
+			j++
+		}
+		if j >= len(data) {
+			return 0, fmt.Errorf("bencode: unterminated integer")
+		}
+		return j + 1, nil
+
+	case data[i] == 'l':
+		j := i + 1
+		for j < len(data) && data[j] != 'e' {
+			next, err := bencodeSkip(data, j)
+			if err != nil {
+				return 0, err
+			}
+			j = next
+		}
+		if j >= len(data) {
+			return 0, fmt.Errorf("bencode: unterminated list")
+		}
+		return j + 1, nil
+
+	case data[i] == 'd':
+		j := i + 1
+		for j < len(data) && data[j] != 'e' {
+			_, next, err := bencodeString(data, j)
+			if err != nil {
+				return 0, err
+			}
+			next, err = bencodeSkip(data, next)
+			if err != nil {
+				return 0, err
+			}
+			j = next
+		}
+		if j >= len(data) {
+			return 0, fmt.Errorf("bencode: unterminated dictionary")
+		}
+		return j + 1, nil
+
+	case data[i] >= '0' && data[i] <= '9':
+		_, next, err := bencodeString(data, i)
+		return next, err
+
+	default:
+		return 0, fmt.Errorf("bencode: invalid type byte %q", data[i])
+	}
+}
+
+// bencodeString parses a length-prefixed bencode string starting at i,
+// returning its value and the offset just past it.
+func bencodeString(data []byte, i int) (string, int, error) {
+	j := i
+	for j < len(data) && data[j] != ':' {
+		j++
+	}
+	if j >= len(data) {
+		return "", 0, fmt.Errorf("bencode: unterminated string length")
+	}
+
+	n, err := strconv.Atoi(string(data[i:j]))
+	if err != nil {
+		return "", 0, fmt.Errorf("bencode: invalid string length: %w", err)
+	}
+	start := j + 1
+	end := start + n
+	if n < 0 || end > len(data) {
+		return "", 0, fmt.Errorf("bencode: string length out of range")
+	}
+	return string(data[start:end]), end, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runExport dumps the whole catalog as JSON or CSV to stdout.
+func runExport() {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	asJSON := fs.Bool("json", true, "export as JSON")
+	asCSV := fs.Bool("csv", false, "export as CSV")
+
+	fs.Parse(os.Args[1:])
+	if fs.NArg() != 1 {
+		errExit("USAGE: %s export [-json|-csv] DB", os.Args[0])
+	}
+
+	store, err := OpenStore(fs.Arg(0))
+	if err != nil {
+		errExit("unable to open catalog: %v", err)
+	}
+	defer store.Close()
+
+	movies, err := store.All()
+	if err != nil {
+		errExit("unable to read catalog: %v", err)
+	}
+
+	if *asCSV {
+		if err := exportCSV(movies); err != nil {
+			errExit("unable to export CSV: %v", err)
+		}
+		return
+	}
+
+	if *asJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(movies); err != nil {
+			errExit("unable to export JSON: %v", err)
+		}
+	}
+}
+
+func exportCSV(movies []Movie) error {
+	w := csv.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	if err := w.Write([]string{"imdb_code", "title", "year", "rating", "genres"}); err != nil {
+		return err
+	}
+	for _, m := range movies {
+		row := []string{m.ImdbCode, m.Title, fmt.Sprintf("%d", m.Year), fmt.Sprintf("%.1f", m.Rating), joinCSV(m.Genres)}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return w.Error()
+}
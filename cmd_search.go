@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runSearch runs a full text search over the catalog's titles and
+// descriptions.
+func runSearch() {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := fs.Int("limit", 20, "maximum number of results to print")
+
+	fs.Parse(os.Args[1:])
+	if fs.NArg() != 2 {
+		errExit("USAGE: %s search [flags] QUERY DB", os.Args[0])
+	}
+	query, dbPath := fs.Arg(0), fs.Arg(1)
+
+	store, err := OpenStore(dbPath)
+	if err != nil {
+		errExit("unable to open catalog: %v", err)
+	}
+	defer store.Close()
+
+	movies, err := store.Search(quoteFTS(query), *limit)
+	if err != nil {
+		errExit("unable to search catalog: %v", err)
+	}
+
+	for _, m := range movies {
+		fmt.Printf("%s (%d) [%s] %.1f\r\n", m.Title, m.Year, m.ImdbCode, m.Rating)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runShow prints the full catalog record for a single movie.
+func runShow() {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	fs.Parse(os.Args[1:])
+	if fs.NArg() != 2 {
+		errExit("USAGE: %s show [flags] IMDB_CODE DB", os.Args[0])
+	}
+	imdbCode, dbPath := fs.Arg(0), fs.Arg(1)
+
+	store, err := OpenStore(dbPath)
+	if err != nil {
+		errExit("unable to open catalog: %v", err)
+	}
+	defer store.Close()
+
+	m, err := store.Show(imdbCode)
+	if err != nil {
+		errExit("unable to show movie: %v", err)
+	}
+
+	fmt.Printf("%s (%d)\r\n", m.Title, m.Year)
+	fmt.Printf("Rating: %.1f  Runtime: %dm  Genres: %v\r\n", m.Rating, m.Runtime, m.Genres)
+	fmt.Printf("%s\r\n", m.DescriptionFull)
+	for _, t := range m.Torrents {
+		fmt.Printf("  %s  seeds=%d peers=%d size=%s\r\n", t.Quality, t.Seeds, t.Peers, t.Size)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// runSync syncs the catalog from yts.am into the SQLite store, then
+// downloads (or hands off to a torrent client) every asset it contains.
+func runSync() {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+
+	clientKind := fs.String("client", "", "submit torrents to a running client instead of saving .torrent files: qbittorrent or transmission")
+	clientURL := fs.String("client-url", "http://localhost:8080", "base URL of the qBittorrent/Transmission Web API")
+	clientUser := fs.String("client-user", "", "username for the torrent client's Web API")
+	clientPass := fs.String("client-pass", "", "password for the torrent client's Web API")
+	clientCategory := fs.String("category", "yts-sync", "category/label to assign to torrents added to the client")
+	clientSavePath := fs.String("save-path", "Movies/{Title} ({Year})/{Quality}", "save-path template passed to the torrent client")
+
+	concurrency := fs.Int("concurrency", 4, "number of assets to download at once")
+	retries := fs.Int("retries", 3, "number of retries for a failed download")
+	timeout := fs.Duration("timeout", 30*time.Second, "HTTP timeout per download attempt")
+
+	genres := fs.String("genre", "", "comma separated list of genres to sync, e.g. Action,Sci-Fi (default: every genre)")
+	quality := fs.String("quality", "", "comma separated list of torrent qualities to keep, e.g. 1080p,2160p (default: every quality)")
+	minRating := fs.Float64("min-rating", 0, "drop movies rated below this")
+	minYear := fs.Int("min-year", 0, "drop movies released before this year")
+	minSeeds := fs.Int("min-seeds", 0, "drop torrents with fewer seeds than this")
+
+	subs := fs.String("subs", "", "comma separated list of subtitle languages to fetch, e.g. en,es,fr (default: none)")
+	subsMinRating := fs.Float64("subs-min-rating", 0, "drop subtitles rated below this")
+	openSubtitlesKey := fs.String("opensubtitles-key", "", "OpenSubtitles API key; when unset subtitles are fetched from YIFY Subtitles")
+
+	tmdbKey := fs.String("tmdb-key", "", "TMDB API key; enables metadata enrichment and a Jellyfin/Kodi library layout")
+	omdbKey := fs.String("omdb-key", "", "OMDB API key; used for enrichment when -tmdb-key is unset")
+
+	fs.Parse(os.Args[1:])
+	if fs.NArg() != 1 {
+		errExit("USAGE: %s sync [flags] DB", os.Args[0])
+	}
+
+	client, err := NewTorrentClient(ClientConfig{
+		Kind:     *clientKind,
+		URL:      *clientURL,
+		User:     *clientUser,
+		Pass:     *clientPass,
+		Category: *clientCategory,
+		SavePath: *clientSavePath,
+	})
+	if err != nil {
+		errExit("unable to set up torrent client: %v", err)
+	}
+
+	store, err := OpenStore(fs.Arg(0))
+	if err != nil {
+		errExit("unable to open catalog: %v", err)
+	}
+	defer store.Close()
+
+	genreList := splitCSV(*genres)
+	if len(genreList) == 0 {
+		genreList = []string{""} // no --genre given: sync the whole catalog
+	}
+
+	filters := Filters{
+		Qualities: toSet(splitCSV(*quality)),
+		MinYear:   *minYear,
+		MinSeeds:  *minSeeds,
+	}
+
+	if err := syncGenres(store, genreList, *minRating, filters); err != nil {
+		errExit("unable to sync catalog: %v", err)
+	}
+
+	movies, err := store.All()
+	if err != nil {
+		errExit("unable to read catalog: %v", err)
+	}
+
+	downloader := NewDownloader(*concurrency, *retries, *timeout)
+
+	subLangs := splitCSV(*subs)
+	var subProvider SubtitleProvider
+	if len(subLangs) > 0 {
+		subProvider = NewSubtitleProvider(*openSubtitlesKey)
+	}
+
+	metadataProvider := NewMetadataProvider(*tmdbKey, *omdbKey)
+	kodiLayout := metadataProvider != nil
+
+	var jobs []DownloadJob
+	var magnets []magnetJob
+	for _, movie := range movies {
+		if metadataProvider != nil && !movie.Enriched {
+			enrichment, err := metadataProvider.Enrich(movie.ImdbCode)
+			if err != nil {
+				perr("unable to enrich %s: %v", movie.Title, err)
+			} else if err := store.MarkEnriched(movie.ID); err != nil {
+				perr("unable to record enrichment for %s: %v", movie.Title, err)
+			}
+			if err := writeNFO(movie, enrichment, movieAssetDir(movie)); err != nil {
+				perr("unable to write NFO for %s: %v", movie.Title, err)
+			}
+		}
+
+		if newLangs := missing(subLangs, movie.SubsLangs); subProvider != nil && len(newLangs) > 0 {
+			subJobs, fetched, err := subtitleJobs(movie, subProvider, newLangs, *subsMinRating)
+			if err != nil {
+				perr("unable to resolve subtitles for %s: %v", movie.Title, err)
+			} else if err := store.MarkSubsLangs(movie.ID, append(movie.SubsLangs, fetched...)); err != nil {
+				perr("unable to record subtitle languages for %s: %v", movie.Title, err)
+			}
+			jobs = append(jobs, subJobs...)
+		}
+
+		j, m := downloadables(movie, client, kodiLayout, *clientSavePath)
+		jobs = append(jobs, j...)
+		magnets = append(magnets, m...)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for p := range downloader.Progress {
+			if p.Err != nil {
+				perr("unable to download %s: %v", p.URL, p.Err)
+			} else if !p.Done {
+				fmt.Printf("%s: %s/%s\r\n", p.URL, formatBytes(p.BytesDone), formatBytes(p.BytesTotal))
+			}
+		}
+	}()
+	downloader.Run(jobs)
+	wg.Wait()
+
+	for _, m := range magnets {
+		if err := client.AddMagnet(m.magnet, *clientCategory, m.savePath); err != nil {
+			perr("unable to add torrent to client: %v", err)
+		}
+	}
+}
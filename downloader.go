@@ -0,0 +1,259 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+)
+
+// DownloadJob describes a single asset to fetch to disk.
+type DownloadJob struct {
+	URL  string
+	Dest string
+	// InfoHash is the expected hex-encoded BitTorrent info-hash of a
+	// downloaded .torrent file, used to verify it against the hash YTS
+	// announced. Left empty for assets that aren't .torrent files.
+	InfoHash string
+}
+
+// Progress reports the state of a single DownloadJob as it moves through
+// the worker pool, so a caller can render a progress bar.
+type Progress struct {
+	URL        string
+	BytesDone  int64
+	BytesTotal int64
+	Done       bool
+	Err        error
+}
+
+// Downloader runs DownloadJobs through a bounded worker pool with retries,
+// Range-based resume and info-hash verification.
+type Downloader struct {
+	Client      *http.Client
+	Concurrency int
+	MaxRetries  int
+	Progress    chan Progress
+}
+
+// NewDownloader builds a Downloader with a shared *http.Client configured
+// with timeout and a yts-sync User-Agent.
+func NewDownloader(concurrency, maxRetries int, timeout time.Duration) *Downloader {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Downloader{
+		Client:      &http.Client{Timeout: timeout},
+		Concurrency: concurrency,
+		MaxRetries:  maxRetries,
+		Progress:    make(chan Progress, concurrency),
+	}
+}
+
+// Run fans jobs out across the worker pool and blocks until every job has
+// been attempted, closing d.Progress once done.
+func (d *Downloader) Run(jobs []DownloadJob) {
+	in := make(chan DownloadJob)
+	done := make(chan struct{})
+
+	for w := 0; w < d.Concurrency; w++ {
+		go func() {
+			for job := range in {
+				d.process(job)
+			}
+			done <- struct{}{}
+		}()
+	}
+
+	go func() {
+		for _, j := range jobs {
+			in <- j
+		}
+		close(in)
+	}()
+
+	for w := 0; w < d.Concurrency; w++ {
+		<-done
+	}
+	close(d.Progress)
+}
+
+func (d *Downloader) process(job DownloadJob) {
+	if _, err := os.Stat(job.Dest); err == nil {
+		d.Progress <- Progress{URL: job.URL, Done: true}
+		return
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= d.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff(attempt))
+		}
+		if err := d.download(job); err != nil {
+			lastErr = err
+			if !retryable(err) {
+				break
+			}
+			continue
+		}
+		d.Progress <- Progress{URL: job.URL, Done: true}
+		return
+	}
+	d.Progress <- Progress{URL: job.URL, Done: true, Err: lastErr}
+}
+
+// download fetches job.URL to job.Dest+".part", resuming via a Range
+// request if a partial file already exists, then atomically renames it
+// into place and verifies its info-hash if one was requested.
+func (d *Downloader) download(job DownloadJob) error {
+	if err := os.MkdirAll(path.Dir(job.Dest), 0755); err != nil {
+		return fmt.Errorf("unable to create directory: %w", err)
+	}
+
+	part := job.Dest + ".part"
+	var offset int64
+	if fi, err := os.Stat(part); err == nil {
+		offset = fi.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, job.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "yts-sync/1.0")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		offset = 0
+	case http.StatusPartialContent:
+		// resuming, offset already set
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the .part file is already complete or corrupt, start over
+		os.Remove(part)
+		offset = 0
+	default:
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("server error: %s", resp.Status)
+		}
+		return permanentError{fmt.Errorf("unexpected status: %s", resp.Status)}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if offset > 0 {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to create file: %w", err)
+	}
+
+	total := offset + resp.ContentLength
+	written, err := io.Copy(&progressWriter{w: f, d: d, job: job, total: total, done: offset}, resp.Body)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("unable to download file: %w", err)
+	}
+	_ = written
+
+	if job.InfoHash != "" {
+		if err := verifyInfoHash(part, job.InfoHash); err != nil {
+			os.Remove(part)
+			return permanentError{err}
+		}
+	}
+
+	return os.Rename(part, job.Dest)
+}
+
+// progressWriter wraps the destination file so each chunk written also
+// reports progress on the Downloader's channel.
+type progressWriter struct {
+	w     io.Writer
+	d     *Downloader
+	job   DownloadJob
+	total int64
+	done  int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	p.d.Progress <- Progress{URL: p.job.URL, BytesDone: p.done, BytesTotal: p.total}
+	return n, err
+}
+
+// verifyInfoHash checks a downloaded .torrent file against its announced
+// BitTorrent info-hash, which is the SHA1 of just the bencoded "info"
+// dictionary rather than of the whole file.
+func verifyInfoHash(path, want string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	start, end, err := torrentInfoSpan(data)
+	if err != nil {
+		return fmt.Errorf("unable to parse torrent: %w", err)
+	}
+
+	sum := sha1.Sum(data[start:end])
+	got := hex.EncodeToString(sum[:])
+	if got != want {
+		return fmt.Errorf("info-hash mismatch: got %s, want %s", got, want)
+	}
+	return nil
+}
+
+// permanentError marks a failure that retrying won't fix, such as a 4xx
+// response.
+type permanentError struct{ err error }
+
+func (p permanentError) Error() string { return p.err.Error() }
+
+func retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, permanent := err.(permanentError)
+	return !permanent
+}
+
+func backoff(attempt int) time.Duration {
+	d := time.Second
+	for i := 1; i < attempt; i++ {
+		d *= 2
+	}
+	if d > 30*time.Second {
+		d = 30 * time.Second
+	}
+	return d
+}
+
+// formatBytes renders n as a human-readable size, e.g. "12.3 MB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for nn := n / unit; nn >= unit; nn /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,78 @@
+package main
+
+import "strings"
+
+// splitCSV splits a comma separated flag value, trimming whitespace and
+// dropping empty entries. An empty or blank s yields a nil slice.
+func splitCSV(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// missing returns the entries of want that aren't already in have, used to
+// fetch only newly requested subtitle languages on a later sync.
+func missing(want, have []string) []string {
+	haveSet := toSet(have)
+	var out []string
+	for _, w := range want {
+		if !haveSet[w] {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// toSet turns a list into a membership set.
+func toSet(items []string) map[string]bool {
+	if len(items) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(items))
+	for _, i := range items {
+		set[i] = true
+	}
+	return set
+}
+
+// Filters narrows the synced catalog down to what the user actually wants
+// to keep, applied after syncing so the watermark still advances over
+// movies that end up filtered out.
+type Filters struct {
+	Qualities map[string]bool // empty means "keep every quality"
+	MinYear   int
+	MinSeeds  int
+}
+
+// apply drops torrents below MinSeeds or not in Qualities, and drops movies
+// entirely that are older than MinYear or left with no torrents.
+func (f Filters) apply(movies []Movie) []Movie {
+	out := movies[:0]
+	for _, m := range movies {
+		if m.Year < f.MinYear {
+			continue
+		}
+
+		torrents := m.Torrents[:0]
+		for _, t := range m.Torrents {
+			if t.Seeds < f.MinSeeds {
+				continue
+			}
+			if len(f.Qualities) > 0 && !f.Qualities[t.Quality] {
+				continue
+			}
+			torrents = append(torrents, t)
+		}
+		if len(torrents) == 0 {
+			continue
+		}
+		m.Torrents = torrents
+		out = append(out, m)
+	}
+	return out
+}
@@ -1,15 +1,10 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"math"
-	"net/http"
 	"os"
 	"path"
 	"strings"
-	"sync"
 )
 
 // Movie represents a movie from yts
@@ -50,6 +45,11 @@ type Movie struct {
 	} `json:"torrents"`
 	DateUploaded     string `json:"date_uploaded"`
 	DateUploadedUnix int    `json:"date_uploaded_unix"`
+
+	// Enriched and SubsLangs are sync state read back from the Store; they
+	// don't come from the yts.am API.
+	Enriched  bool     `json:"-"`
+	SubsLangs []string `json:"-"`
 }
 
 // Payload is the api payload
@@ -62,67 +62,26 @@ type Payload struct {
 	} `json:"data"`
 }
 
-// APIURL is the url for API
-const APIURL = "https://yts.am/api/v2/list_movies.json?limit=50&page=%d"
-
 func main() {
-	if len(os.Args) != 2 {
-		errExit("USAGE: %s FILE", os.Args[0])
-	}
-	var movies []Movie
-
-	f, err := os.OpenFile(os.Args[1], os.O_CREATE|os.O_RDWR, 0644)
-	if err != nil {
-		errExit("unable to open file: %v", err)
-	}
-
-	err = json.NewDecoder(f).Decode(&movies)
-	if err != nil {
-		if s, err := f.Stat(); err != nil && s.Size() > 0 {
-			errExit("unable to read file: %v", err)
-		}
-	}
-
-	j := 2
-	cl := len(movies)
-	ttd := cl
-	for i := 1; i < j; i++ {
-		resp, err := http.DefaultClient.Get(fmt.Sprintf(APIURL, i))
-		if err != nil {
-			perr("error unable to response: %v", err)
-			continue
-		}
-
-		payload := new(Payload)
-		err = json.NewDecoder(resp.Body).Decode(payload)
-		if err != nil {
-			perr("unable to decode JSON: %v", err)
-			continue
-		}
-
-		tl := payload.Data.MovieCount
-		dl := tl - cl
-		ml := len(payload.Data.Movies)
-		index := int(math.Min(float64(ml), float64(dl)))
-		j = int(math.Ceil(float64(dl)/50.0)) + 1
-		ttd += index
-
-		movies = append(movies, payload.Data.Movies[:index]...)
-		fmt.Printf("Page: %03d of %03d, Total Movies: %06d, Movies: %06d\r\n", i, j, tl, ttd)
-	}
-
-	f.Truncate(0)
-	f.Seek(0, 0)
-	err = json.NewEncoder(f).Encode(movies)
-	if err != nil {
-		perr("unable to encode JSON: %v", err)
-		return
+	if len(os.Args) < 2 {
+		errExit("USAGE: %s <sync|search|show|export> [flags] DB", os.Args[0])
 	}
 
-	for _, movie := range movies {
-		downloadRes(movie)
+	cmd := os.Args[1]
+	os.Args = append(os.Args[:1], os.Args[2:]...)
+
+	switch cmd {
+	case "sync":
+		runSync()
+	case "search":
+		runSearch()
+	case "show":
+		runShow()
+	case "export":
+		runExport()
+	default:
+		errExit("unknown command %q, expected sync, search, show or export", cmd)
 	}
-
 }
 
 func errExit(format string, args ...interface{}) {
@@ -134,73 +93,65 @@ func perr(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, format+"\r\n", args...)
 }
 
-func download(link string) error {
+// destPath turns a yts.am URL into the local path assets are mirrored to,
+// defaulting to a .torrent extension for extensionless torrent links.
+func destPath(link string) string {
 	p := strings.Replace(link, "https://yts.am/", "", 1)
-	dir := path.Dir(p)
-	ext := path.Ext(p)
-	if ext == "" {
-		ext = ".torrent"
-	} else {
-		ext = ""
-	}
-
-	p = p + ext
-
-	if _, err := os.Stat(p); !os.IsNotExist(err) {
-		perr("file already exists: %v", p)
-		return err
-	}
-
-	err := os.MkdirAll(dir, 644)
-	if err != nil {
-		perr("unable to create directory: %v", err)
-		return err
-	}
-
-	resp, err := http.DefaultClient.Get(link)
-	if err != nil {
-		return err
-	}
-
-	f, err := os.Create(p)
-	if err != nil {
-		perr("unable to create file: %v", err)
-		return err
+	if path.Ext(p) == "" {
+		p += ".torrent"
 	}
+	return p
+}
 
-	_, err = io.Copy(f, resp.Body)
-	if err != nil {
-		perr("unable to download file: %v", err)
-		return err
-	}
-	return nil
+// magnetJob is a torrent handed off to a TorrentClient instead of being
+// downloaded to disk.
+type magnetJob struct {
+	magnet   string
+	savePath string
 }
 
-func downloadRes(movie Movie) {
-	var wg sync.WaitGroup
-	assets := downloadables(movie)
-	wg.Add(len(assets))
-	for _, res := range assets {
-		go func(l string) {
-			err := download(l)
-			if err != nil {
-				perr("unable to download asset: %v", err)
-			}
-			wg.Done()
-		}(res)
+// downloadables splits a movie's assets into DownloadJobs for the worker
+// pool and, when client handoff is enabled, magnetJobs for its torrents.
+// When kodiLayout is set the assets are written into a Jellyfin/Kodi
+// library directory (poster.jpg, fanart.jpg, "{Title} ({Year}).{Quality}.torrent")
+// instead of a flat mirror of the yts.am URL path.
+func downloadables(movie Movie, client TorrentClient, kodiLayout bool, savePathTmpl string) ([]DownloadJob, []magnetJob) {
+	var jobs []DownloadJob
+
+	if kodiLayout {
+		dir := movieAssetDir(movie)
+		jobs = append(jobs,
+			DownloadJob{URL: movie.LargeCoverImage, Dest: path.Join(dir, "poster.jpg")},
+			DownloadJob{URL: movie.BackgroundImageOriginal, Dest: path.Join(dir, "fanart.jpg")},
+		)
+	} else {
+		images := []string{
+			movie.BackgroundImage,
+			movie.BackgroundImageOriginal,
+			movie.SmallCoverImage,
+			movie.MediumCoverImage,
+			movie.LargeCoverImage,
+		}
+		for _, url := range images {
+			jobs = append(jobs, DownloadJob{URL: url, Dest: destPath(url)})
+		}
 	}
-	wg.Wait()
-}
 
-func downloadables(movie Movie) []string {
-	res := make([]string, len(movie.Torrents)+5)
-	res[0] = movie.BackgroundImage
-	res[1] = movie.BackgroundImageOriginal
-	res[2] = movie.SmallCoverImage
-	res[3] = movie.MediumCoverImage
-	res[4] = movie.LargeCoverImage
-	for i, t := range movie.Torrents {
-		res[5+i] = t.URL
+	var magnets []magnetJob
+	for _, t := range movie.Torrents {
+		if client != nil {
+			magnets = append(magnets, magnetJob{
+				magnet:   magnetLink(t.Hash, movie.Title),
+				savePath: savePath(savePathTmpl, movie, t.Quality),
+			})
+			continue
+		}
+
+		dest := destPath(t.URL)
+		if kodiLayout {
+			dest = path.Join(movieAssetDir(movie), fmt.Sprintf("%s (%d).%s.torrent", movie.Title, movie.Year, t.Quality))
+		}
+		jobs = append(jobs, DownloadJob{URL: t.URL, Dest: dest, InfoHash: strings.ToLower(t.Hash)})
 	}
-	return res
+	return jobs, magnets
 }
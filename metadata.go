@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Enrichment holds the metadata YTS doesn't provide, joined in from a
+// MetadataProvider by IMDb code.
+type Enrichment struct {
+	Tagline    string
+	Cast       []CastMember
+	Directors  []string
+	Keywords   []string
+	Collection string
+	Backdrops  []string // higher resolution backdrops than YTS's single BackgroundImage
+	Parental   string   // e.g. MPAA/IMDb parental guidance rating
+}
+
+// CastMember is one entry of a movie's cast.
+type CastMember struct {
+	Name string
+	Role string
+}
+
+// MetadataProvider enriches a movie looked up by its IMDb code.
+type MetadataProvider interface {
+	Enrich(imdbCode string) (*Enrichment, error)
+}
+
+// NewMetadataProvider returns the TMDB provider when tmdbKey is set,
+// otherwise the OMDB provider when omdbKey is set, otherwise nil.
+func NewMetadataProvider(tmdbKey, omdbKey string) MetadataProvider {
+	if tmdbKey != "" {
+		return &tmdbProvider{apiKey: tmdbKey, client: http.DefaultClient}
+	}
+	if omdbKey != "" {
+		return &omdbProvider{apiKey: omdbKey, client: http.DefaultClient}
+	}
+	return nil
+}
+
+// tmdbProvider enriches via TMDB's find-by-external-id and credits
+// endpoints, which gives the cast/crew and a fuller backdrop pack.
+type tmdbProvider struct {
+	apiKey string
+	client *http.Client
+}
+
+func (p *tmdbProvider) Enrich(imdbCode string) (*Enrichment, error) {
+	find, err := p.get(fmt.Sprintf("https://api.themoviedb.org/3/find/%s?external_source=imdb_id&api_key=%s", imdbCode, p.apiKey))
+	if err != nil {
+		return nil, err
+	}
+
+	var findResult struct {
+		MovieResults []struct {
+			ID int `json:"id"`
+		} `json:"movie_results"`
+	}
+	if err := json.Unmarshal(find, &findResult); err != nil {
+		return nil, fmt.Errorf("tmdb: unable to decode find response: %w", err)
+	}
+	if len(findResult.MovieResults) == 0 {
+		return nil, fmt.Errorf("tmdb: no match for %s", imdbCode)
+	}
+	id := findResult.MovieResults[0].ID
+
+	details, err := p.get(fmt.Sprintf("https://api.themoviedb.org/3/movie/%d?api_key=%s&append_to_response=credits,keywords,images", id, p.apiKey))
+	if err != nil {
+		return nil, err
+	}
+
+	var d struct {
+		Tagline string `json:"tagline"`
+		BelongsToCollection struct {
+			Name string `json:"name"`
+		} `json:"belongs_to_collection"`
+		Credits struct {
+			Cast []struct {
+				Name      string `json:"name"`
+				Character string `json:"character"`
+			} `json:"cast"`
+			Crew []struct {
+				Name string `json:"name"`
+				Job  string `json:"job"`
+			} `json:"crew"`
+		} `json:"credits"`
+		Keywords struct {
+			Keywords []struct {
+				Name string `json:"name"`
+			} `json:"keywords"`
+		} `json:"keywords"`
+		Images struct {
+			Backdrops []struct {
+				FilePath string `json:"file_path"`
+			} `json:"backdrops"`
+		} `json:"images"`
+	}
+	if err := json.Unmarshal(details, &d); err != nil {
+		return nil, fmt.Errorf("tmdb: unable to decode movie response: %w", err)
+	}
+
+	e := &Enrichment{Tagline: d.Tagline, Collection: d.BelongsToCollection.Name}
+	for _, c := range d.Credits.Cast {
+		e.Cast = append(e.Cast, CastMember{Name: c.Name, Role: c.Character})
+	}
+	for _, c := range d.Credits.Crew {
+		if c.Job == "Director" {
+			e.Directors = append(e.Directors, c.Name)
+		}
+	}
+	for _, k := range d.Keywords.Keywords {
+		e.Keywords = append(e.Keywords, k.Name)
+	}
+	for _, b := range d.Images.Backdrops {
+		e.Backdrops = append(e.Backdrops, "https://image.tmdb.org/t/p/original"+b.FilePath)
+	}
+	return e, nil
+}
+
+func (p *tmdbProvider) get(url string) ([]byte, error) {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tmdb: unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// omdbProvider enriches via OMDB's single flat lookup, which is enough for
+// cast, director and the IMDb parental rating but has no artwork.
+type omdbProvider struct {
+	apiKey string
+	client *http.Client
+}
+
+func (p *omdbProvider) Enrich(imdbCode string) (*Enrichment, error) {
+	resp, err := p.client.Get(fmt.Sprintf("http://www.omdbapi.com/?i=%s&apikey=%s", imdbCode, p.apiKey))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("omdb: unexpected status %s", resp.Status)
+	}
+
+	var d struct {
+		Response string `json:"Response"`
+		Error    string `json:"Error"`
+		Rated    string `json:"Rated"`
+		Director string `json:"Director"`
+		Actors   string `json:"Actors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, fmt.Errorf("omdb: unable to decode JSON: %w", err)
+	}
+	if d.Response == "False" {
+		return nil, fmt.Errorf("omdb: %s", d.Error)
+	}
+
+	e := &Enrichment{Parental: d.Rated}
+	for _, name := range splitCSV(d.Director) {
+		e.Directors = append(e.Directors, name)
+	}
+	for _, name := range splitCSV(d.Actors) {
+		e.Cast = append(e.Cast, CastMember{Name: name})
+	}
+	return e, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path"
+)
+
+// nfoTemplate mirrors Kodi's <movie> schema closely enough for Jellyfin and
+// Kodi to pick up the library without a network scrape.
+const nfoTemplate = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<movie>
+  <title>%s</title>
+  <year>%d</year>
+  <rating>%.1f</rating>
+  <plot>%s</plot>
+  <tagline>%s</tagline>
+  <mpaa>%s</mpaa>
+%s%s%s%s  <uniqueid type="imdb" default="true">%s</uniqueid>
+</movie>
+`
+
+// writeNFO renders movie.nfo for a movie into dir, folding in whatever an
+// Enrichment found (cast, directors, collection) when one is available.
+func writeNFO(movie Movie, e *Enrichment, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("unable to create directory: %w", err)
+	}
+
+	var genres string
+	for _, g := range movie.Genres {
+		genres += fmt.Sprintf("  <genre>%s</genre>\n", html.EscapeString(g))
+	}
+
+	var tagline, directors, cast, collection string
+	if e != nil {
+		tagline = html.EscapeString(e.Tagline)
+		for _, d := range e.Directors {
+			directors += fmt.Sprintf("  <director>%s</director>\n", html.EscapeString(d))
+		}
+		for _, c := range e.Cast {
+			cast += fmt.Sprintf("  <actor>\n    <name>%s</name>\n    <role>%s</role>\n  </actor>\n", html.EscapeString(c.Name), html.EscapeString(c.Role))
+		}
+		if e.Collection != "" {
+			collection = fmt.Sprintf("  <set>%s</set>\n", html.EscapeString(e.Collection))
+		}
+	}
+
+	nfo := fmt.Sprintf(nfoTemplate,
+		html.EscapeString(movie.Title),
+		movie.Year,
+		movie.Rating,
+		html.EscapeString(movie.DescriptionFull),
+		tagline,
+		movie.MpaRating,
+		genres, directors, cast, collection,
+		movie.ImdbCode,
+	)
+
+	return os.WriteFile(path.Join(dir, "movie.nfo"), []byte(nfo), 0644)
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS movies (
+	id                        INTEGER PRIMARY KEY,
+	imdb_code                 TEXT UNIQUE NOT NULL,
+	url                       TEXT,
+	title                     TEXT,
+	title_english             TEXT,
+	title_long                TEXT,
+	slug                      TEXT,
+	year                      INTEGER,
+	rating                    REAL,
+	runtime                   INTEGER,
+	download_count            INTEGER,
+	like_count                INTEGER,
+	description_intro         TEXT,
+	description_full          TEXT,
+	yt_trailer_code           TEXT,
+	language                  TEXT,
+	mpa_rating                TEXT,
+	background_image          TEXT,
+	background_image_original TEXT,
+	small_cover_image         TEXT,
+	medium_cover_image        TEXT,
+	large_cover_image         TEXT,
+	date_uploaded             TEXT,
+	date_uploaded_unix        INTEGER,
+	enriched                  INTEGER NOT NULL DEFAULT 0,
+	subs_langs                TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS torrents (
+	movie_id           INTEGER NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+	url                TEXT,
+	hash               TEXT,
+	quality            TEXT,
+	seeds              INTEGER,
+	peers              INTEGER,
+	size               TEXT,
+	size_bytes         INTEGER,
+	date_uploaded      TEXT,
+	date_uploaded_unix INTEGER,
+	PRIMARY KEY (movie_id, hash)
+);
+
+CREATE TABLE IF NOT EXISTS genres (
+	id   INTEGER PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS movie_genres (
+	movie_id INTEGER NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+	genre_id INTEGER NOT NULL REFERENCES genres(id) ON DELETE CASCADE,
+	PRIMARY KEY (movie_id, genre_id)
+);
+
+CREATE TABLE IF NOT EXISTS sync_state (
+	key   TEXT PRIMARY KEY,
+	value TEXT
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS movies_fts USING fts5 (
+	imdb_code UNINDEXED,
+	title,
+	description_full
+);
+`
+
+// Store is the SQLite-backed catalog, replacing the flat JSON mirror so
+// the catalog scales to tens of thousands of movies without reloading
+// and rewriting the whole file on every sync.
+type Store struct {
+	db *sql.DB
+}
+
+// OpenStore opens (and, if needed, creates) the catalog database at path.
+func OpenStore(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to apply schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Watermark returns the highest date_uploaded_unix observed by the last
+// sync, or 0 if the catalog has never been synced.
+func (s *Store) Watermark() (int, error) {
+	var v string
+	err := s.db.QueryRow(`SELECT value FROM sync_state WHERE key = 'watermark'`).Scan(&v)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var watermark int
+	_, err = fmt.Sscanf(v, "%d", &watermark)
+	return watermark, err
+}
+
+// SetWatermark persists the highest date_uploaded_unix seen so far.
+func (s *Store) SetWatermark(watermark int) error {
+	_, err := s.db.Exec(`INSERT INTO sync_state (key, value) VALUES ('watermark', ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, fmt.Sprintf("%d", watermark))
+	return err
+}
+
+// TorrentCount returns how many torrents are on record for a movie, used to
+// detect a new quality showing up on a movie that's otherwise unchanged.
+func (s *Store) TorrentCount(movieID int) (int, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM torrents WHERE movie_id = ?`, movieID).Scan(&n)
+	return n, err
+}
+
+// MarkEnriched records that a movie's metadata enrichment and NFO have
+// already been written, so future syncs don't re-call the metadata
+// provider for it.
+func (s *Store) MarkEnriched(movieID int) error {
+	_, err := s.db.Exec(`UPDATE movies SET enriched = 1 WHERE id = ?`, movieID)
+	return err
+}
+
+// MarkSubsLangs records additional subtitle languages fetched for a movie,
+// merged with whatever was recorded before, so future syncs only fetch
+// languages that are newly requested.
+func (s *Store) MarkSubsLangs(movieID int, langs []string) error {
+	set := toSet(langs)
+	merged := make([]string, 0, len(set))
+	for lang := range set {
+		merged = append(merged, lang)
+	}
+	_, err := s.db.Exec(`UPDATE movies SET subs_langs = ? WHERE id = ?`, joinCSV(merged), movieID)
+	return err
+}
+
+// Upsert inserts a movie or, if its IMDb code is already on record, updates
+// it in place along with its torrents and genres.
+func (s *Store) Upsert(m Movie) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO movies (
+			id, imdb_code, url, title, title_english, title_long, slug, year, rating, runtime,
+			download_count, like_count, description_intro, description_full, yt_trailer_code,
+			language, mpa_rating, background_image, background_image_original, small_cover_image,
+			medium_cover_image, large_cover_image, date_uploaded, date_uploaded_unix
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(imdb_code) DO UPDATE SET
+			url = excluded.url, title = excluded.title, title_english = excluded.title_english,
+			title_long = excluded.title_long, slug = excluded.slug, year = excluded.year,
+			rating = excluded.rating, runtime = excluded.runtime, download_count = excluded.download_count,
+			like_count = excluded.like_count, description_intro = excluded.description_intro,
+			description_full = excluded.description_full, yt_trailer_code = excluded.yt_trailer_code,
+			language = excluded.language, mpa_rating = excluded.mpa_rating,
+			background_image = excluded.background_image, background_image_original = excluded.background_image_original,
+			small_cover_image = excluded.small_cover_image, medium_cover_image = excluded.medium_cover_image,
+			large_cover_image = excluded.large_cover_image, date_uploaded = excluded.date_uploaded,
+			date_uploaded_unix = excluded.date_uploaded_unix`,
+		m.ID, m.ImdbCode, m.URL, m.Title, m.TitleEnglish, m.TitleLong, m.Slug, m.Year, m.Rating, m.Runtime,
+		m.DownloadCount, m.LikeCount, m.DescriptionIntro, m.DescriptionFull, m.YtTrailerCode,
+		m.Language, m.MpaRating, m.BackgroundImage, m.BackgroundImageOriginal, m.SmallCoverImage,
+		m.MediumCoverImage, m.LargeCoverImage, m.DateUploaded, m.DateUploadedUnix,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to upsert movie: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM torrents WHERE movie_id = ?`, m.ID); err != nil {
+		return err
+	}
+	for _, t := range m.Torrents {
+		if _, err := tx.Exec(`INSERT INTO torrents (movie_id, url, hash, quality, seeds, peers, size, size_bytes, date_uploaded, date_uploaded_unix)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			m.ID, t.URL, t.Hash, t.Quality, t.Seeds, t.Peers, t.Size, t.SizeBytes, t.DateUploaded, t.DateUploadedUnix); err != nil {
+			return fmt.Errorf("unable to insert torrent: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM movie_genres WHERE movie_id = ?`, m.ID); err != nil {
+		return err
+	}
+	for _, g := range m.Genres {
+		if _, err := tx.Exec(`INSERT INTO genres (name) VALUES (?) ON CONFLICT(name) DO NOTHING`, g); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO movie_genres (movie_id, genre_id)
+			SELECT ?, id FROM genres WHERE name = ?`, m.ID, g); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM movies_fts WHERE imdb_code = ?`, m.ImdbCode); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO movies_fts (imdb_code, title, description_full) VALUES (?, ?, ?)`,
+		m.ImdbCode, m.Title, m.DescriptionFull); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// All returns every movie in the catalog, hydrated with a constant number
+// of queries regardless of how many rows there are.
+func (s *Store) All() ([]Movie, error) {
+	ids, err := s.queryIDs(`SELECT id FROM movies ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	return s.moviesByIDs(ids)
+}
+
+// Show looks a movie up by its IMDb code.
+func (s *Store) Show(imdbCode string) (*Movie, error) {
+	var id int
+	err := s.db.QueryRow(`SELECT id FROM movies WHERE imdb_code = ?`, imdbCode).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no movie with imdb code %s", imdbCode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	movies, err := s.moviesByIDs([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	return &movies[0], nil
+}
+
+// Search runs a full text search over title and description_full.
+func (s *Store) Search(query string, limit int) ([]Movie, error) {
+	ids, err := s.queryIDs(`
+		SELECT m.id FROM movies_fts f
+		JOIN movies m ON m.imdb_code = f.imdb_code
+		WHERE movies_fts MATCH ?
+		ORDER BY rank
+		LIMIT ?`, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to search: %w", err)
+	}
+	return s.moviesByIDs(ids)
+}
+
+// queryIDs runs a query whose sole column is a movie id, preserving row
+// order so ordered callers like Search (ranked by FTS5) keep their order.
+func (s *Store) queryIDs(query string, args ...interface{}) ([]int, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// moviesByIDs hydrates a batch of movie rows, along with their genres and
+// torrents, in 3 queries total rather than 3 per movie, so it scales to
+// catalogs with tens of thousands of entries. The result preserves the
+// order of ids.
+func (s *Store) moviesByIDs(ids []int) ([]Movie, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	in := strings.Join(placeholders, ",")
+
+	byID := make(map[int]*Movie, len(ids))
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT id, imdb_code, url, title, title_english, title_long, slug, year, rating, runtime,
+			download_count, like_count, description_intro, description_full, yt_trailer_code,
+			language, mpa_rating, background_image, background_image_original, small_cover_image,
+			medium_cover_image, large_cover_image, date_uploaded, date_uploaded_unix,
+			enriched, subs_langs
+		FROM movies WHERE id IN (%s)`, in), args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		m := &Movie{}
+		var subsLangs string
+		if err := rows.Scan(
+			&m.ID, &m.ImdbCode, &m.URL, &m.Title, &m.TitleEnglish, &m.TitleLong, &m.Slug, &m.Year, &m.Rating, &m.Runtime,
+			&m.DownloadCount, &m.LikeCount, &m.DescriptionIntro, &m.DescriptionFull, &m.YtTrailerCode,
+			&m.Language, &m.MpaRating, &m.BackgroundImage, &m.BackgroundImageOriginal, &m.SmallCoverImage,
+			&m.MediumCoverImage, &m.LargeCoverImage, &m.DateUploaded, &m.DateUploadedUnix,
+			&m.Enriched, &subsLangs,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.SubsLangs = splitCSV(subsLangs)
+		byID[m.ID] = m
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	grows, err := s.db.Query(fmt.Sprintf(`
+		SELECT mg.movie_id, g.name FROM movie_genres mg
+		JOIN genres g ON g.id = mg.genre_id
+		WHERE mg.movie_id IN (%s)`, in), args...)
+	if err != nil {
+		return nil, err
+	}
+	for grows.Next() {
+		var movieID int
+		var name string
+		if err := grows.Scan(&movieID, &name); err != nil {
+			grows.Close()
+			return nil, err
+		}
+		if m, ok := byID[movieID]; ok {
+			m.Genres = append(m.Genres, name)
+		}
+	}
+	if err := grows.Err(); err != nil {
+		grows.Close()
+		return nil, err
+	}
+	grows.Close()
+
+	trows, err := s.db.Query(fmt.Sprintf(`
+		SELECT movie_id, url, hash, quality, seeds, peers, size, size_bytes, date_uploaded, date_uploaded_unix
+		FROM torrents WHERE movie_id IN (%s) ORDER BY movie_id, quality`, in), args...)
+	if err != nil {
+		return nil, err
+	}
+	for trows.Next() {
+		var movieID int
+		var t struct {
+			URL              string
+			Hash             string
+			Quality          string
+			Seeds            int
+			Peers            int
+			Size             string
+			SizeBytes        int
+			DateUploaded     string
+			DateUploadedUnix int
+		}
+		if err := trows.Scan(&movieID, &t.URL, &t.Hash, &t.Quality, &t.Seeds, &t.Peers, &t.Size, &t.SizeBytes, &t.DateUploaded, &t.DateUploadedUnix); err != nil {
+			trows.Close()
+			return nil, err
+		}
+		if m, ok := byID[movieID]; ok {
+			m.Torrents = append(m.Torrents, struct {
+				URL              string `json:"url"`
+				Hash             string `json:"hash"`
+				Quality          string `json:"quality"`
+				Seeds            int    `json:"seeds"`
+				Peers            int    `json:"peers"`
+				Size             string `json:"size"`
+				SizeBytes        int    `json:"size_bytes"`
+				DateUploaded     string `json:"date_uploaded"`
+				DateUploadedUnix int    `json:"date_uploaded_unix"`
+			}{t.URL, t.Hash, t.Quality, t.Seeds, t.Peers, t.Size, t.SizeBytes, t.DateUploaded, t.DateUploadedUnix})
+		}
+	}
+	if err := trows.Err(); err != nil {
+		trows.Close()
+		return nil, err
+	}
+	trows.Close()
+
+	movies := make([]Movie, 0, len(ids))
+	for _, id := range ids {
+		if m, ok := byID[id]; ok {
+			movies = append(movies, *m)
+		}
+	}
+	return movies, nil
+}
+
+// quoteFTS escapes a raw search phrase for use in an FTS5 MATCH query.
+func quoteFTS(q string) string {
+	return `"` + strings.ReplaceAll(q, `"`, `""`) + `"`
+}
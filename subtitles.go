@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// Subtitle is a single language track resolved for a movie.
+type Subtitle struct {
+	Lang   string
+	URL    string
+	Rating float64
+}
+
+// SubtitleProvider resolves subtitle download URLs for a movie's IMDb code.
+type SubtitleProvider interface {
+	Subtitles(imdbCode string, langs []string) ([]Subtitle, error)
+}
+
+// NewSubtitleProvider returns the YIFY Subtitles provider, or the
+// OpenSubtitles REST provider when apiKey is set.
+func NewSubtitleProvider(apiKey string) SubtitleProvider {
+	if apiKey != "" {
+		return &openSubtitlesProvider{apiKey: apiKey, client: http.DefaultClient}
+	}
+	return &yifySubtitlesProvider{client: http.DefaultClient}
+}
+
+// yifySubtitlesProvider queries YIFY Subtitles, which indexes its catalog
+// by IMDb code and needs no API key.
+type yifySubtitlesProvider struct {
+	client *http.Client
+}
+
+func (p *yifySubtitlesProvider) Subtitles(imdbCode string, langs []string) ([]Subtitle, error) {
+	resp, err := p.client.Get(fmt.Sprintf("https://yifysubtitles.ch/api/v2/subtitles/%s", imdbCode))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("yifysubtitles: unexpected status %s", resp.Status)
+	}
+
+	var payload struct {
+		Subtitles []struct {
+			Lang   string  `json:"lang"`
+			URL    string  `json:"url"`
+			Rating float64 `json:"rating"`
+		} `json:"subtitles"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("yifysubtitles: unable to decode JSON: %w", err)
+	}
+
+	wanted := toSet(langs)
+	var subs []Subtitle
+	for _, s := range payload.Subtitles {
+		if len(wanted) > 0 && !wanted[s.Lang] {
+			continue
+		}
+		subs = append(subs, Subtitle{Lang: s.Lang, URL: s.URL, Rating: s.Rating})
+	}
+	return subs, nil
+}
+
+// openSubtitlesProvider queries the OpenSubtitles REST API, which requires
+// an API key and returns ratings in the 0-10 range like YTS movie ratings.
+type openSubtitlesProvider struct {
+	apiKey string
+	client *http.Client
+}
+
+func (p *openSubtitlesProvider) Subtitles(imdbCode string, langs []string) ([]Subtitle, error) {
+	v := url.Values{}
+	v.Set("imdb_id", imdbCode)
+	v.Set("languages", joinCSV(langs))
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.opensubtitles.com/api/v1/subtitles?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Api-Key", p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("opensubtitles: unexpected status %s", resp.Status)
+	}
+
+	var payload struct {
+		Data []struct {
+			Attributes struct {
+				Language string  `json:"language"`
+				Ratings  float64 `json:"ratings"`
+				Files    []struct {
+					URL string `json:"file_download_url"`
+				} `json:"files"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("opensubtitles: unable to decode JSON: %w", err)
+	}
+
+	var subs []Subtitle
+	for _, d := range payload.Data {
+		for _, f := range d.Attributes.Files {
+			subs = append(subs, Subtitle{Lang: d.Attributes.Language, URL: f.URL, Rating: d.Attributes.Ratings})
+		}
+	}
+	return subs, nil
+}
+
+// movieAssetDir is where a movie's subtitles (and eventually its other
+// per-movie assets) are written, named the same way as the default client
+// save-path so the two stay consistent.
+func movieAssetDir(movie Movie) string {
+	return path.Join("Movies", fmt.Sprintf("%s (%d)", movie.Title, movie.Year))
+}
+
+// subtitleJobs resolves and queues subtitle downloads for a movie, dropping
+// languages rated below minRating, so they participate in the same worker
+// pool, retries and resume logic as every other asset. It also reports
+// which of langs the provider actually answered for, so a caller can record
+// them as handled and not ask again on a later sync; on error it reports no
+// languages as handled, so a transient outage gets retried instead of
+// silently marked as done.
+func subtitleJobs(movie Movie, provider SubtitleProvider, langs []string, minRating float64) (jobs []DownloadJob, fetched []string, err error) {
+	subs, err := provider.Subtitles(movie.ImdbCode, langs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]bool, len(subs))
+	for _, s := range subs {
+		if !seen[s.Lang] {
+			seen[s.Lang] = true
+			fetched = append(fetched, s.Lang)
+		}
+		if s.Rating < minRating {
+			continue
+		}
+		dest := path.Join(movieAssetDir(movie), fmt.Sprintf("%s.%s.srt", movie.Title, s.Lang))
+		jobs = append(jobs, DownloadJob{URL: s.URL, Dest: dest})
+	}
+	return jobs, fetched, nil
+}
+
+func joinCSV(items []string) string {
+	out := ""
+	for i, s := range items {
+		if i > 0 {
+			out += ","
+		}
+		out += s
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// listMoviesURL builds a list_movies.json request for a single genre,
+// sorted by date_added desc so incremental sync can find its watermark.
+// genre may be empty to query the whole catalog.
+func listMoviesURL(genre string, minRating float64, page int) string {
+	v := url.Values{}
+	v.Set("limit", "50")
+	v.Set("page", fmt.Sprintf("%d", page))
+	v.Set("sort_by", "date_added")
+	v.Set("order_by", "desc")
+	if genre != "" {
+		v.Set("genre", genre)
+	}
+	if minRating > 0 {
+		v.Set("minimum_rating", fmt.Sprintf("%g", minRating))
+	}
+	return "https://yts.am/api/v2/list_movies.json?" + v.Encode()
+}
+
+// catalog upserts movies into the Store as they're fetched instead of
+// accumulating the whole catalog in memory, so a sync scales to tens of
+// thousands of movies. It is safe for concurrent use so one goroutine per
+// genre can merge results at once.
+type catalog struct {
+	store   *Store
+	filters Filters
+
+	mu            sync.Mutex
+	origWatermark int
+	newWatermark  int
+	updated       int
+}
+
+func newCatalog(store *Store, filters Filters) (*catalog, error) {
+	wm, err := store.Watermark()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read watermark: %w", err)
+	}
+	return &catalog{store: store, filters: filters, origWatermark: wm, newWatermark: wm}, nil
+}
+
+// merge stores a freshly fetched movie, after applying filters, if it is
+// new or its filtered torrent list changed. It reports whether the genre
+// walk that produced raw has caught up with the last run's watermark.
+func (c *catalog) merge(raw Movie) (caughtUp bool) {
+	filtered := c.filters.apply([]Movie{raw})
+
+	m := raw
+	m.Torrents = nil
+	if len(filtered) > 0 {
+		m = filtered[0]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	existingCount, err := c.store.TorrentCount(raw.ID)
+	if err != nil {
+		perr("unable to check existing torrents for %s: %v", raw.Title, err)
+	}
+	changed := existingCount != len(m.Torrents)
+
+	if raw.DateUploadedUnix <= c.origWatermark && !changed {
+		return true
+	}
+
+	if len(m.Torrents) > 0 {
+		if err := c.store.Upsert(m); err != nil {
+			perr("unable to store %s: %v", m.Title, err)
+		}
+	}
+
+	if raw.DateUploadedUnix > c.newWatermark {
+		c.newWatermark = raw.DateUploadedUnix
+	}
+	c.updated++
+	return false
+}
+
+// syncGenre walks list_movies.json pages for a single genre until it either
+// runs out of movies or catches up with the catalog's watermark, merging
+// every movie it sees.
+func syncGenre(client *http.Client, genre string, minRating float64, c *catalog) {
+	for page := 1; ; page++ {
+		resp, err := client.Get(listMoviesURL(genre, minRating, page))
+		if err != nil {
+			perr("error unable to response: %v", err)
+			return
+		}
+
+		payload := new(Payload)
+		err = json.NewDecoder(resp.Body).Decode(payload)
+		resp.Body.Close()
+		if err != nil {
+			perr("unable to decode JSON: %v", err)
+			return
+		}
+
+		if len(payload.Data.Movies) == 0 {
+			return
+		}
+
+		stop := false
+		for _, m := range payload.Data.Movies {
+			if c.merge(m) {
+				stop = true
+				break
+			}
+		}
+		if stop {
+			return
+		}
+	}
+}
+
+// syncGenres fetches every genre in genres concurrently (a single empty
+// genre means "the whole catalog"), upserting matching movies into store
+// and advancing its watermark.
+func syncGenres(store *Store, genres []string, minRating float64, filters Filters) error {
+	c, err := newCatalog(store, filters)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(genres))
+	for _, genre := range genres {
+		go func(genre string) {
+			defer wg.Done()
+			syncGenre(http.DefaultClient, genre, minRating, c)
+		}(genre)
+	}
+	wg.Wait()
+
+	fmt.Printf("Synced %d genre(s), %d movie(s) new or updated\r\n", len(genres), c.updated)
+
+	return store.SetWatermark(c.newWatermark)
+}